@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/ninech/apis"
@@ -19,6 +20,16 @@ import (
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// inClusterEnvVar, if set to a truthy value, makes New() use the in-cluster
+// configuration instead of discovering a kubeconfig. This is useful when
+// nctl is embedded in a Job, CronJob or Operator running inside a Nine
+// cluster.
+const inClusterEnvVar = "NCTL_IN_CLUSTER"
+
+// inClusterNamespaceFile is where the Kubernetes client libraries read the
+// current namespace from when running inside a cluster.
+const inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 type Client struct {
 	runtimeclient.WithWatch
 	Config         *rest.Config
@@ -30,11 +41,26 @@ type Client struct {
 // and namespace. The kubeconfig is discovered like this:
 // * KUBECONFIG environment variable pointing at a file
 // * $HOME/.kube/config if exists
+//
+// If no kubeconfig can be found, or the NCTL_IN_CLUSTER environment variable
+// is set to a truthy value, the in-cluster configuration is used instead.
+// This allows nctl to be run from within a Job, CronJob or Operator inside a
+// Nine cluster without a kubeconfig mounted.
 func New(apiClusterContext, namespace string) (*Client, error) {
+	return newClient(apiClusterContext, namespace, inClusterRequested())
+}
+
+// NewInCluster returns a new Client configured from the in-cluster service
+// account instead of discovering a kubeconfig.
+func NewInCluster(namespace string) (*Client, error) {
+	return newClient("", namespace, true)
+}
+
+func newClient(apiClusterContext, namespace string, preferInCluster bool) (*Client, error) {
 	client := &Client{
 		Namespace: namespace,
 	}
-	if err := client.loadConfig(apiClusterContext); err != nil {
+	if err := client.loadConfig(apiClusterContext, preferInCluster); err != nil {
 		return nil, err
 	}
 
@@ -43,6 +69,12 @@ func New(apiClusterContext, namespace string) (*Client, error) {
 		return nil, err
 	}
 
+	// The mapper is built for the whole scheme up front, rather than lazily as
+	// kinds are requested: every command resolves its kinds through this same
+	// embedded client, so an empty-by-default mapper would break any Get/List
+	// for a kind that hadn't been registered first, and there's no single
+	// call site to hook a lazy registration into without touching every
+	// command.
 	mapper := apis.StaticRESTMapper(scheme)
 	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
 
@@ -71,7 +103,11 @@ func NewScheme() (*runtime.Scheme, error) {
 }
 
 // adapted from https://github.com/kubernetes-sigs/controller-runtime/blob/4c9c9564e4652bbdec14a602d6196d8622500b51/pkg/client/config/config.go#L116
-func (c *Client) loadConfig(context string) error {
+func (c *Client) loadConfig(context string, preferInCluster bool) error {
+	if preferInCluster {
+		return c.loadInClusterConfig()
+	}
+
 	loadingRules, err := LoadingRules()
 	if err != nil {
 		return err
@@ -79,6 +115,12 @@ func (c *Client) loadConfig(context string) error {
 
 	cfg, namespace, err := loadConfigWithContext("", loadingRules, context)
 	if err != nil {
+		// no kubeconfig could be discovered, fall back to the in-cluster
+		// configuration so nctl keeps working when embedded in a
+		// Job/CronJob/Operator without a mounted kubeconfig.
+		if inClusterErr := c.loadInClusterConfig(); inClusterErr == nil {
+			return nil
+		}
 		return err
 	}
 	if c.Namespace == "" {
@@ -90,6 +132,36 @@ func (c *Client) loadConfig(context string) error {
 	return nil
 }
 
+// loadInClusterConfig configures the client from the in-cluster service
+// account as the Kubernetes client libraries do. KubeconfigPath is left
+// empty, as there is no kubeconfig file to write a user-kubeconfig to.
+func (c *Client) loadInClusterConfig() error {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+
+	if c.Namespace == "" {
+		ns, err := os.ReadFile(inClusterNamespaceFile)
+		if err != nil {
+			return fmt.Errorf("unable to determine in-cluster namespace: %w", err)
+		}
+		c.Namespace = string(ns)
+	}
+
+	c.Config = cfg
+	return nil
+}
+
+func inClusterRequested() bool {
+	v, ok := os.LookupEnv(inClusterEnvVar)
+	if !ok {
+		return false
+	}
+	requested, _ := strconv.ParseBool(v)
+	return requested
+}
+
 func (c *Client) Name(name string) types.NamespacedName {
 	return types.NamespacedName{Name: name, Namespace: c.Namespace}
 }
@@ -10,15 +10,39 @@ import (
 
 	infrastructure "github.com/ninech/apis/infrastructure/v1alpha1"
 	"github.com/ninech/nctl/api"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// managedContextExtension marks a kubeconfig context as written by
+// `nctl auth cluster --all`, so a later `--prune` run can tell it apart from
+// contexts the user created or imported themselves.
+const managedContextExtension = "nctl.ninech.com/managed"
+
 type ClusterCmd struct {
-	Name       string `arg:"" help:"Name of the cluster to authenticate with. Also accepts 'name/namespace' format."`
-	ExecPlugin bool   `help:"Automatically run exec plugin after writing the kubeconfig."`
+	Name          string `arg:"" help:"Name of the cluster to authenticate with. Also accepts 'name/namespace' format." optional:""`
+	ExecPlugin    bool   `help:"Automatically run exec plugin after writing the kubeconfig."`
+	All           bool   `help:"Import all KubernetesClusters the caller can see into the kubeconfig instead of authenticating with a single one." default:"false"`
+	AllNamespaces bool   `help:"When used with --all, list clusters of all namespaces instead of just the current one." short:"A" default:"false"`
+	Prune         bool   `help:"When used with --all, remove nctl-managed contexts whose cluster no longer exists." default:"false"`
 }
 
 func (a *ClusterCmd) Run(ctx context.Context, client *api.Client) error {
+	if client.KubeconfigPath == "" {
+		return fmt.Errorf("no kubeconfig path available to write to (are you using an in-cluster configuration?)")
+	}
+
+	if a.All {
+		return a.runAll(ctx, client)
+	}
+
+	if len(a.Name) == 0 {
+		return fmt.Errorf("name is required unless --all is set")
+	}
+
 	name, err := clusterName(a.Name, client.Namespace)
 	if err != nil {
 		return err
@@ -29,28 +53,88 @@ func (a *ClusterCmd) Run(ctx context.Context, client *api.Client) error {
 		return err
 	}
 
+	cfg, err := clusterAPIConfig(cluster)
+	if err != nil {
+		return fmt.Errorf("unable to create kubeconfig: %w", err)
+	}
+
+	if err := login(cfg, client.KubeconfigPath, runExecPlugin(a.ExecPlugin), switchCurrentContext()); err != nil {
+		return fmt.Errorf("error logging in to cluster %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// runAll imports every KubernetesCluster the caller can see into
+// client.KubeconfigPath with a single write of the whole file. The file is
+// rewritten in place, not replaced atomically. Contexts nctl didn't
+// create are left untouched; contexts it did create are tagged with
+// managedContextExtension so a later run with --prune can safely remove the
+// ones whose cluster no longer exists.
+func (a *ClusterCmd) runAll(ctx context.Context, client *api.Client) error {
+	clusterList := &infrastructure.KubernetesClusterList{}
+	listOpts := []runtimeclient.ListOption{}
+	if !a.AllNamespaces {
+		listOpts = append(listOpts, runtimeclient.InNamespace(client.Namespace))
+	}
+	if err := client.List(ctx, clusterList, listOpts...); err != nil {
+		return fmt.Errorf("unable to list clusters: %w", err)
+	}
+
+	merged, err := clientcmd.LoadFromFile(client.KubeconfigPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("unable to load existing kubeconfig: %w", err)
+		}
+		merged = clientcmdapi.NewConfig()
+	}
+
+	seen := make(map[string]bool, len(clusterList.Items))
+	for i := range clusterList.Items {
+		cluster := &clusterList.Items[i]
+		name := ContextName(cluster)
+		seen[name] = true
+
+		cfg, err := clusterAPIConfig(cluster)
+		if err != nil {
+			return fmt.Errorf("unable to create kubeconfig for cluster %s: %w", name, err)
+		}
+
+		mergeManagedContext(merged, cfg, name)
+	}
+
+	if a.Prune {
+		pruneManagedContexts(merged, seen)
+	}
+
+	return clientcmd.WriteToFile(*merged, client.KubeconfigPath)
+}
+
+// clusterAPIConfig builds the *clientcmdapi.Config used to authenticate with
+// cluster, using the current command name as the exec plugin.
+func clusterAPIConfig(cluster *infrastructure.KubernetesCluster) (*clientcmdapi.Config, error) {
 	apiEndpoint, err := url.Parse(cluster.Status.AtProvider.APIEndpoint)
 	if err != nil {
-		return fmt.Errorf("invalid cluster API endpoint: %w", err)
+		return nil, fmt.Errorf("invalid cluster API endpoint: %w", err)
 	}
 
 	issuerURL, err := url.Parse(cluster.Status.AtProvider.OIDCIssuerURL)
 	if err != nil {
-		return fmt.Errorf("invalid cluster OIDC issuer url: %w", err)
+		return nil, fmt.Errorf("invalid cluster OIDC issuer url: %w", err)
 	}
 
 	caCert, err := base64.StdEncoding.DecodeString(cluster.Status.AtProvider.APICACert)
 	if err != nil {
-		return fmt.Errorf("unable to decode API CA certificate: %w", err)
+		return nil, fmt.Errorf("unable to decode API CA certificate: %w", err)
 	}
 
 	// not sure if this should ever happen but better than getting a panic
 	if len(os.Args) == 0 {
-		return fmt.Errorf("could not get command name from os.Args")
+		return nil, fmt.Errorf("could not get command name from os.Args")
 	}
 	command := os.Args[0]
 
-	cfg, err := newAPIConfig(
+	return newAPIConfig(
 		apiEndpoint,
 		issuerURL,
 		command,
@@ -58,15 +142,76 @@ func (a *ClusterCmd) Run(ctx context.Context, client *api.Client) error {
 		overrideName(ContextName(cluster)),
 		setCACert(caCert),
 	)
-	if err != nil {
-		return fmt.Errorf("unable to create kubeconfig: %w", err)
+}
+
+// mergeManagedContext copies the cluster/user/context created for src into
+// dest, tagging the context as nctl-managed under name.
+func mergeManagedContext(dest, src *clientcmdapi.Config, name string) {
+	for k, v := range src.Clusters {
+		dest.Clusters[k] = v
+	}
+	for k, v := range src.AuthInfos {
+		dest.AuthInfos[k] = v
+	}
+	for k, v := range src.Contexts {
+		if v.Extensions == nil {
+			v.Extensions = map[string]runtime.Object{}
+		}
+		v.Extensions[managedContextExtension] = &runtime.Unknown{Raw: []byte("true")}
+		dest.Contexts[k] = v
 	}
+	if dest.CurrentContext == "" {
+		dest.CurrentContext = name
+	}
+}
 
-	if err := login(cfg, client.KubeconfigPath, runExecPlugin(a.ExecPlugin), switchCurrentContext()); err != nil {
-		return fmt.Errorf("error logging in to cluster %s: %w", name, err)
+// pruneManagedContexts removes nctl-managed contexts whose cluster is no
+// longer in seen. Their cluster and user entries are only removed along
+// with them if no remaining context (managed or not) still references them.
+func pruneManagedContexts(cfg *clientcmdapi.Config, seen map[string]bool) {
+	stale := []string{}
+	for name, c := range cfg.Contexts {
+		if seen[name] {
+			continue
+		}
+		if _, managed := c.Extensions[managedContextExtension]; !managed {
+			continue
+		}
+		stale = append(stale, name)
 	}
 
-	return nil
+	for _, name := range stale {
+		c := cfg.Contexts[name]
+		delete(cfg.Contexts, name)
+		if cfg.CurrentContext == name {
+			cfg.CurrentContext = ""
+		}
+
+		if !clusterReferenced(cfg, c.Cluster) {
+			delete(cfg.Clusters, c.Cluster)
+		}
+		if !authInfoReferenced(cfg, c.AuthInfo) {
+			delete(cfg.AuthInfos, c.AuthInfo)
+		}
+	}
+}
+
+func clusterReferenced(cfg *clientcmdapi.Config, cluster string) bool {
+	for _, c := range cfg.Contexts {
+		if c.Cluster == cluster {
+			return true
+		}
+	}
+	return false
+}
+
+func authInfoReferenced(cfg *clientcmdapi.Config, authInfo string) bool {
+	for _, c := range cfg.Contexts {
+		if c.AuthInfo == authInfo {
+			return true
+		}
+	}
+	return false
 }
 
 func clusterName(name, namespace string) (types.NamespacedName, error) {
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func managedContext(cluster, authInfo string) *clientcmdapi.Context {
+	return &clientcmdapi.Context{
+		Cluster:  cluster,
+		AuthInfo: authInfo,
+		Extensions: map[string]runtime.Object{
+			managedContextExtension: &runtime.Unknown{Raw: []byte("true")},
+		},
+	}
+}
+
+func TestPruneManagedContexts(t *testing.T) {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["gone"] = &clientcmdapi.Cluster{Server: "https://gone.example.org"}
+	cfg.Clusters["kept"] = &clientcmdapi.Cluster{Server: "https://kept.example.org"}
+	cfg.Clusters["shared"] = &clientcmdapi.Cluster{Server: "https://shared.example.org"}
+	cfg.AuthInfos["gone"] = &clientcmdapi.AuthInfo{Token: "gone"}
+	cfg.AuthInfos["kept"] = &clientcmdapi.AuthInfo{Token: "kept"}
+
+	// a stale, nctl-managed context whose cluster/user are unreferenced
+	// elsewhere: should be fully removed.
+	cfg.Contexts["stale/ns"] = managedContext("gone", "gone")
+	// a still-present, nctl-managed context: must survive.
+	cfg.Contexts["kept/ns"] = managedContext("kept", "kept")
+	// a stale, nctl-managed context whose cluster is still referenced by a
+	// context the user created themselves: the cluster entry must survive.
+	cfg.Contexts["stale-shared/ns"] = managedContext("shared", "kept")
+	cfg.Contexts["user-context"] = &clientcmdapi.Context{Cluster: "shared", AuthInfo: "kept"}
+
+	cfg.CurrentContext = "stale/ns"
+
+	seen := map[string]bool{"kept/ns": true}
+	pruneManagedContexts(cfg, seen)
+
+	if _, ok := cfg.Contexts["stale/ns"]; ok {
+		t.Error("expected stale managed context to be removed")
+	}
+	if _, ok := cfg.Clusters["gone"]; ok {
+		t.Error("expected unreferenced cluster of stale context to be removed")
+	}
+	if _, ok := cfg.AuthInfos["gone"]; ok {
+		t.Error("expected unreferenced user of stale context to be removed")
+	}
+
+	if _, ok := cfg.Contexts["kept/ns"]; !ok {
+		t.Error("expected still-present managed context to survive")
+	}
+	if _, ok := cfg.Contexts["user-context"]; !ok {
+		t.Error("expected user-created context to survive")
+	}
+	if _, ok := cfg.Clusters["shared"]; !ok {
+		t.Error("expected cluster still referenced by a surviving context to survive")
+	}
+	if _, ok := cfg.AuthInfos["kept"]; !ok {
+		t.Error("expected user still referenced by surviving contexts to survive")
+	}
+
+	if cfg.CurrentContext != "" {
+		t.Errorf("expected current-context pointing at a removed context to be cleared, got %q", cfg.CurrentContext)
+	}
+}
@@ -2,27 +2,43 @@ package get
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"text/tabwriter"
+	"time"
 
 	iam "github.com/ninech/apis/iam/v1alpha1"
 	"github.com/ninech/nctl/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 type apiServiceAccountsCmd struct {
-	Name            string `arg:"" help:"Name of the API Service Account to get. If omitted all in the namespace will be listed." default:""`
-	PrintToken      bool   `help:"Print the bearer token of the Account. Requires name to be set." default:"false"`
-	PrintKubeconfig bool   `help:"Print the kubeconfig of the Account. Requires name to be set." default:"false"`
+	Name            string        `arg:"" help:"Name of the API Service Account to get. If omitted all in the namespace will be listed." default:""`
+	PrintToken      bool          `help:"Print the bearer token of the Account. Requires name to be set." default:"false"`
+	PrintKubeconfig bool          `help:"Print the kubeconfig of the Account. Requires name to be set." default:"false"`
+	Wait            bool          `help:"Wait until the token/kubeconfig is available instead of erroring out if it is not ready yet. Requires --print-token or --print-kubeconfig." default:"false"`
+	WaitTimeout     time.Duration `help:"Maximum time to wait for the token/kubeconfig to become available." default:"30s"`
 }
 
+// errSecretNotReady is used within getConnectionSecretKey to signal that the
+// connection secret, or the requested key within it, does not exist yet.
+var errSecretNotReady = errors.New("connection secret not ready yet")
+
 const (
 	tokenKey      = "token"
 	kubeconfigKey = "kubeconfig"
 )
 
 func (asa *apiServiceAccountsCmd) Run(ctx context.Context, client *api.Client, get *Cmd) error {
-	header := get.Output == full
+	if asa.Wait && !asa.PrintToken && !asa.PrintKubeconfig {
+		return fmt.Errorf("--wait requires --print-token or --print-kubeconfig to be set")
+	}
+
+	if !validOutputFormat(get.Output) {
+		return fmt.Errorf("unsupported output format %q, must be one of: full, short, json, yaml, jsonpath=<expression>", get.Output)
+	}
 
 	if len(asa.Name) != 0 {
 		sa := &iam.APIServiceAccount{}
@@ -35,10 +51,15 @@ func (asa *apiServiceAccountsCmd) Run(ctx context.Context, client *api.Client, g
 		}
 
 		if asa.PrintKubeconfig {
-			return asa.printKubeconfig(ctx, client, sa)
+			return asa.printKubeconfig(ctx, client, sa, get.Output)
 		}
 
-		return asa.print([]iam.APIServiceAccount{*sa}, header)
+		if structuredOutputRequested(get.Output) {
+			setGVK(client.Scheme(), sa)
+			return writeOutput(os.Stdout, sa, get.Output)
+		}
+
+		return asa.print([]iam.APIServiceAccount{*sa}, get.Output == full)
 	}
 
 	if asa.PrintToken || asa.PrintKubeconfig {
@@ -56,7 +77,12 @@ func (asa *apiServiceAccountsCmd) Run(ctx context.Context, client *api.Client, g
 		return nil
 	}
 
-	return asa.print(asaList.Items, header)
+	if structuredOutputRequested(get.Output) {
+		setGVK(client.Scheme(), asaList)
+		return writeOutput(os.Stdout, asaList, get.Output)
+	}
+
+	return asa.print(asaList.Items, get.Output == full)
 }
 
 func (asa *apiServiceAccountsCmd) print(sas []iam.APIServiceAccount, header bool) error {
@@ -74,14 +100,9 @@ func (asa *apiServiceAccountsCmd) print(sas []iam.APIServiceAccount, header bool
 }
 
 func (asa *apiServiceAccountsCmd) printToken(ctx context.Context, client *api.Client, sa *iam.APIServiceAccount) error {
-	secret, err := client.GetConnectionSecret(ctx, sa)
+	token, err := asa.getConnectionSecretKey(ctx, client, sa, tokenKey)
 	if err != nil {
-		return fmt.Errorf("unable to get connection secret: %w", err)
-	}
-
-	token, ok := secret.Data[tokenKey]
-	if !ok {
-		return fmt.Errorf("secret of API Service Account %s has no token", sa.Name)
+		return err
 	}
 
 	fmt.Printf("%s\n", token)
@@ -89,18 +110,79 @@ func (asa *apiServiceAccountsCmd) printToken(ctx context.Context, client *api.Cl
 	return nil
 }
 
-func (asa *apiServiceAccountsCmd) printKubeconfig(ctx context.Context, client *api.Client, sa *iam.APIServiceAccount) error {
-	secret, err := client.GetConnectionSecret(ctx, sa)
+func (asa *apiServiceAccountsCmd) printKubeconfig(ctx context.Context, client *api.Client, sa *iam.APIServiceAccount, output string) error {
+	kc, err := asa.getConnectionSecretKey(ctx, client, sa, kubeconfigKey)
 	if err != nil {
-		return fmt.Errorf("unable to get connection secret: %w", err)
+		return err
 	}
 
-	kc, ok := secret.Data[kubeconfigKey]
-	if !ok {
-		return fmt.Errorf("secret of API Service Account %s has no kubeconfig", sa.Name)
+	if !structuredOutputRequested(output) {
+		fmt.Printf("%s", kc)
+		return nil
 	}
 
-	fmt.Printf("%s", kc)
+	// render as a clientcmdapi/v1 fragment so it can be merged into an
+	// existing kubeconfig, e.g. via `KUBECONFIG=a.yaml:b.yaml kubectl config view --flatten`.
+	cfg, err := clientcmd.Load(kc)
+	if err != nil {
+		return fmt.Errorf("unable to parse kubeconfig of API Service Account %s: %w", sa.Name, err)
+	}
 
-	return nil
+	return writeKubeconfigOutput(os.Stdout, cfg, output)
+}
+
+// getConnectionSecretKey reads key from the connection secret of sa. If
+// asa.Wait is set, it polls until the secret and key exist instead of
+// erroring out immediately, as the connection secret is populated
+// asynchronously by the control plane after creation.
+func (asa *apiServiceAccountsCmd) getConnectionSecretKey(ctx context.Context, client *api.Client, sa *iam.APIServiceAccount, key string) ([]byte, error) {
+	get := func() ([]byte, error) {
+		secret, err := client.GetConnectionSecret(ctx, sa)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, errSecretNotReady
+			}
+			return nil, err
+		}
+
+		value, ok := secret.Data[key]
+		if !ok || len(value) == 0 {
+			return nil, errSecretNotReady
+		}
+
+		return value, nil
+	}
+
+	if !asa.Wait {
+		value, err := get()
+		if err != nil {
+			if errors.Is(err, errSecretNotReady) {
+				return nil, fmt.Errorf("secret of API Service Account %s has no %s yet, consider using --wait", sa.Name, key)
+			}
+			return nil, fmt.Errorf("unable to get connection secret: %w", err)
+		}
+		return value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, asa.WaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		value, err := get()
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, errSecretNotReady) {
+			return nil, fmt.Errorf("unable to get connection secret: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out after %s waiting for %s of API Service Account %s to become available", asa.WaitTimeout, key, sa.Name)
+		case <-ticker.C:
+		}
+	}
 }
@@ -0,0 +1,39 @@
+package get
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ninech/nctl/api"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// output formats understood by the tabwriter based printers.
+const (
+	full  = "full"
+	short = "short"
+)
+
+// Cmd is the `get` command, gathering the resource specific subcommands and
+// the flags shared between all of them.
+type Cmd struct {
+	Output        string `help:"Output format. One of: full, short, json, yaml, jsonpath=<expression>." short:"o" default:"full"`
+	AllNamespaces bool   `help:"Apply the command to the resources of all namespaces." short:"A"`
+
+	APIServiceAccounts apiServiceAccountsCmd `cmd:"" name:"apiserviceaccounts" aliases:"asa" help:"Get API Service Accounts."`
+}
+
+// list retrieves l, scoped to the client's namespace unless allNamespaces is
+// set.
+func list(ctx context.Context, client *api.Client, l runtimeclient.ObjectList, allNamespaces bool) error {
+	opts := []runtimeclient.ListOption{}
+	if !allNamespaces {
+		opts = append(opts, runtimeclient.InNamespace(client.Namespace))
+	}
+
+	return client.List(ctx, l, opts...)
+}
+
+func printEmptyMessage(kind, namespace string) {
+	fmt.Printf("no %s found in namespace %q\n", kind, namespace)
+}
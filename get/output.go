@@ -0,0 +1,143 @@
+package get
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// structured output formats, in addition to the tabwriter based "full"/
+// "short" formats.
+const (
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	jsonPathPrefix = "jsonpath="
+)
+
+// structuredOutputRequested reports whether output requests one of the
+// machine readable formats handled by writeOutput, rather than the default
+// tabwriter based "full"/"short" formats.
+func structuredOutputRequested(output string) bool {
+	return output == outputJSON || output == outputYAML || strings.HasPrefix(output, jsonPathPrefix)
+}
+
+// validOutputFormat reports whether output is a supported value for the
+// shared `-o`/`--output` flag: "full", "short", "json", "yaml" or
+// "jsonpath=<expression>".
+func validOutputFormat(output string) bool {
+	return output == full || output == short || structuredOutputRequested(output)
+}
+
+// writeOutput renders obj to w in the format requested by output, which is
+// one of "json", "yaml" or "jsonpath=<expression>". obj is expected to carry
+// its apiVersion/kind (see setGVK) so Crossplane managed resource fields
+// round-trip the same way they do through kubectl.
+func writeOutput(w io.Writer, obj interface{}, output string) error {
+	if path, ok := strings.CutPrefix(output, jsonPathPrefix); ok {
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		return writeJSONPath(w, b, path)
+	}
+
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(obj)
+	case outputYAML:
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of: json, yaml, jsonpath=<expression>", output)
+	}
+}
+
+// writeJSONPath evaluates expr against the JSON document in b and writes the
+// result to w. Missing keys are treated as empty, matching kubectl's
+// `-o jsonpath=` behaviour, so a query like `{.items[*].spec.forProvider.role}`
+// doesn't error out on an empty list or a item missing that field.
+func writeJSONPath(w io.Writer, b []byte, expr string) error {
+	var data interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	jp := jsonpath.New("output")
+	jp.AllowMissingKeys(true)
+	template := expr
+	if !strings.HasPrefix(template, "{") {
+		template = fmt.Sprintf("{%s}", template)
+	}
+	if err := jp.Parse(template); err != nil {
+		return fmt.Errorf("invalid jsonpath expression %q: %w", expr, err)
+	}
+
+	return jp.Execute(w, data)
+}
+
+// setGVK stamps obj's apiVersion/kind from scheme, so that structured output
+// of a single object looks the same as output read back out of a List.
+func setGVK(scheme *runtime.Scheme, obj runtime.Object) {
+	gvks, _, err := scheme.ObjectKinds(obj)
+	if err != nil || len(gvks) == 0 {
+		return
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+}
+
+// writeKubeconfigOutput renders cfg in the format requested by output.
+// Unlike writeOutput, this goes through the versioned clientcmdapi/v1 codec
+// instead of marshalling the internal clientcmdapi.Config directly: the
+// internal type's map-keyed Clusters/AuthInfos/Contexts don't round-trip as
+// a loadable kubeconfig, so a plain json.Marshal/yaml.Marshal of it produces
+// a document that can't be merged back in via KUBECONFIG.
+func writeKubeconfigOutput(w io.Writer, cfg *clientcmdapi.Config, output string) error {
+	encoded, err := runtime.Encode(clientcmdlatest.Codec, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to encode kubeconfig: %w", err)
+	}
+	// clientcmdlatest.Codec serializes to YAML; convert it so the json and
+	// jsonpath formats below work on JSON.
+	versioned, err := yaml.YAMLToJSON(encoded)
+	if err != nil {
+		return fmt.Errorf("unable to encode kubeconfig: %w", err)
+	}
+
+	if path, ok := strings.CutPrefix(output, jsonPathPrefix); ok {
+		return writeJSONPath(w, versioned, path)
+	}
+
+	switch output {
+	case outputJSON:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, versioned, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err := w.Write(buf.Bytes())
+		return err
+	case outputYAML:
+		b, err := yaml.JSONToYAML(versioned)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of: json, yaml, jsonpath=<expression>", output)
+	}
+}
@@ -0,0 +1,97 @@
+package get
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type testObject struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func TestWriteOutput(t *testing.T) {
+	obj := testObject{Name: "foo", Role: "admin"}
+
+	cases := map[string]struct {
+		output string
+		want   string
+	}{
+		"json": {
+			output: outputJSON,
+			want:   "\"name\": \"foo\"",
+		},
+		"yaml": {
+			output: outputYAML,
+			want:   "name: foo",
+		},
+		"jsonpath": {
+			output: "jsonpath={.role}",
+			want:   "admin",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeOutput(&buf, obj, tc.output); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), tc.want) {
+				t.Fatalf("expected output to contain %q, got %q", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestValidOutputFormat(t *testing.T) {
+	valid := []string{full, short, outputJSON, outputYAML, "jsonpath={.items[*].metadata.name}"}
+	for _, output := range valid {
+		if !validOutputFormat(output) {
+			t.Errorf("expected %q to be a valid output format", output)
+		}
+	}
+
+	invalid := []string{"toml", "", "jsonyaml"}
+	for _, output := range invalid {
+		if validOutputFormat(output) {
+			t.Errorf("expected %q to be rejected as an output format", output)
+		}
+	}
+}
+
+func TestWriteOutputUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, testObject{}, "toml"); err == nil {
+		t.Fatal("expected an error for an unsupported output format")
+	}
+}
+
+func TestWriteJSONPathAllowsMissingKeys(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSONPath(&buf, []byte(`{"items":[]}`), "{.items[*].spec.forProvider.role}"); err != nil {
+		t.Fatalf("expected missing keys to be tolerated, got: %s", err)
+	}
+}
+
+func TestWriteKubeconfigOutputIsListForm(t *testing.T) {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["test"] = &clientcmdapi.Cluster{Server: "https://example.org"}
+	cfg.Contexts["test"] = &clientcmdapi.Context{Cluster: "test"}
+	cfg.CurrentContext = "test"
+
+	var buf bytes.Buffer
+	if err := writeKubeconfigOutput(&buf, cfg, outputYAML); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "test:") {
+		t.Fatalf("expected list-form kubeconfig (no map keys), got: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "name: test") {
+		t.Fatalf("expected cluster/context name to be rendered as a list entry, got: %s", buf.String())
+	}
+}